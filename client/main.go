@@ -2,59 +2,147 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 )
 
 const (
-	serverURL     = "http://localhost:8080/cotacao"
+	serverBaseURL = "http://localhost:8080/cotacao"
 	clientTimeout = 300 * time.Millisecond
 	outputFile    = "cotacao.txt"
+	defaultPair   = "USD-BRL"
 )
 
 type quoteResponse struct {
 	Bid string `json:"bid"`
 }
 
+// pairList implementa flag.Value para permitir múltiplas flags --pair
+// repetidas (ex.: --pair USD-BRL --pair EUR-BRL).
+type pairList []string
+
+func (p *pairList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pairList) Set(v string) error {
+	*p = append(*p, strings.ToUpper(strings.TrimSpace(v)))
+	return nil
+}
+
+// requestTiming registra os timestamps capturados via httptrace ao longo de
+// uma chamada HTTP, usados para logar DNS/connect/TTFB/total como uma sonda
+// sintética.
+type requestTiming struct {
+	start             time.Time
+	dnsStart, dnsDone time.Time
+	connectStart      time.Time
+	connectDone       time.Time
+	firstByte         time.Time
+}
+
+func (t *requestTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.dnsDone = time.Now() },
+		ConnectStart:         func(string, string) { t.connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { t.connectDone = time.Now() },
+		GotFirstResponseByte: func() { t.firstByte = time.Now() },
+	}
+}
+
+func (t *requestTiming) logFields(total time.Duration) []any {
+	fields := []any{"total_ms", total.Milliseconds()}
+	if !t.dnsDone.IsZero() {
+		fields = append(fields, "dns_ms", t.dnsDone.Sub(t.dnsStart).Milliseconds())
+	}
+	if !t.connectDone.IsZero() {
+		fields = append(fields, "connect_ms", t.connectDone.Sub(t.connectStart).Milliseconds())
+	}
+	if !t.firstByte.IsZero() {
+		fields = append(fields, "ttfb_ms", t.firstByte.Sub(t.start).Milliseconds())
+	}
+	return fields
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
 func main() {
+	var pairs pairList
+	flag.Var(&pairs, "pair", "par de moeda a consultar (repetível, ex.: --pair USD-BRL --pair EUR-BRL)")
+	flag.Parse()
+	if len(pairs) == 0 {
+		pairs = pairList{defaultPair}
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil)).With("request_id", newRequestID())
+
 	ctx, cancel := context.WithTimeout(context.Background(), clientTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+	timing := &requestTiming{start: time.Now()}
+	ctx = httptrace.WithClientTrace(ctx, timing.trace())
+
+	reqURL := serverBaseURL + "?pair=" + url.QueryEscape(strings.Join(pairs, ","))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
-		log.Fatalf("erro ao criar request: %v", err)
+		logger.Error("erro ao criar request", "error", err)
+		os.Exit(1)
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			log.Fatalf("timeout ao chamar servidor (>%v): %v", clientTimeout, err)
+			logger.Error("timeout ao chamar servidor", "timeout", clientTimeout, "error", err)
+			os.Exit(1)
 		}
-		log.Fatalf("erro ao chamar servidor: %v", err)
+		logger.Error("erro ao chamar servidor", "error", err)
+		os.Exit(1)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("servidor retornou status %d", resp.StatusCode)
+		logger.Error("servidor retornou status inesperado", "status", resp.StatusCode)
+		os.Exit(1)
 	}
 
-	var q quoteResponse
-	if err := json.NewDecoder(resp.Body).Decode(&q); err != nil {
-		log.Fatalf("erro ao decodificar resposta do servidor: %v", err)
+	var quotes map[string]quoteResponse
+	if err := json.NewDecoder(resp.Body).Decode(&quotes); err != nil {
+		logger.Error("erro ao decodificar resposta do servidor", "error", err)
+		os.Exit(1)
 	}
-	if q.Bid == "" {
-		log.Fatalf("resposta do servidor sem campo 'bid'")
+
+	var lines []string
+	for _, pair := range pairs {
+		q, ok := quotes[pair]
+		if !ok || q.Bid == "" {
+			logger.Error("resposta do servidor sem cotação para o par", "pair", pair)
+			os.Exit(1)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", pair, q.Bid))
 	}
 
-	content := fmt.Sprintf("Dólar: %s", q.Bid)
+	content := strings.Join(lines, "\n") + "\n"
 	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
-		log.Fatalf("erro ao escrever arquivo %s: %v", outputFile, err)
+		logger.Error("erro ao escrever arquivo de saída", "file", outputFile, "error", err)
+		os.Exit(1)
 	}
 
-	log.Printf("Cotação salva em %s: %s", outputFile, content)
+	fields := append([]any{"pairs", strings.Join(pairs, ","), "output_file", outputFile}, timing.logFields(time.Since(timing.start))...)
+	logger.Info("cotação obtida", fields...)
 }
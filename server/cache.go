@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheStatus é reportado no header X-Cache e nos logs, permitindo medir a
+// taxa de acerto do cache em produção.
+type cacheStatus string
+
+const (
+	cacheHit   cacheStatus = "HIT"
+	cacheStale cacheStatus = "STALE"
+	cacheMiss  cacheStatus = "MISS"
+)
+
+// cacheEntry guarda a última awesomeAPIResponse obtida com sucesso para um
+// conjunto de pares específico.
+type cacheEntry struct {
+	value     awesomeAPIResponse
+	fetchedAt time.Time
+}
+
+// quoteCache mantém uma cacheEntry por conjunto de pares (chave = pares
+// solicitados, unidos por vírgula) e serve stale-while-revalidate: abaixo de
+// freshTTL devolve o valor em cache direto, entre freshTTL e staleTTL devolve
+// o valor em cache e dispara uma única atualização em background (protegida
+// por singleflight contra stampede).
+type quoteCache struct {
+	freshTTL time.Duration
+	staleTTL time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+
+	sf singleflight.Group
+}
+
+func newQuoteCache(freshTTL, staleTTL time.Duration) *quoteCache {
+	return &quoteCache{freshTTL: freshTTL, staleTTL: staleTTL, entries: make(map[string]cacheEntry)}
+}
+
+// get devolve a cotação em cache para key (HIT/STALE) ou busca uma nova via
+// refresh (MISS), colapsando chamadas concorrentes com singleflight usando
+// key como chave.
+func (c *quoteCache) get(ctx context.Context, key string, refresh func(ctx context.Context) (awesomeAPIResponse, error)) (awesomeAPIResponse, cacheStatus, error) {
+	c.mu.RLock()
+	entry, hasValue := c.entries[key]
+	c.mu.RUnlock()
+	age := time.Since(entry.fetchedAt)
+
+	if hasValue && age < c.freshTTL {
+		return entry.value, cacheHit, nil
+	}
+
+	if hasValue && age < c.staleTTL {
+		c.refreshInBackground(key, refresh)
+		return entry.value, cacheStale, nil
+	}
+
+	v, err, _ := c.sf.Do(key, func() (interface{}, error) {
+		fresh, err := refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, fresh)
+		return fresh, nil
+	})
+	if err != nil {
+		if hasValue {
+			slog.Warn("cache: atualização síncrona falhou, servindo valor expirado", "key", key, "error", err)
+			return entry.value, cacheStale, nil
+		}
+		return nil, cacheMiss, err
+	}
+	return v.(awesomeAPIResponse), cacheMiss, nil
+}
+
+// refreshInBackground dispara no máximo uma atualização concorrente por
+// chave de singleflight; chamadas subsequentes enquanto ela está em voo são
+// descartadas silenciosamente, pois o valor em cache já está sendo servido.
+func (c *quoteCache) refreshInBackground(key string, refresh func(ctx context.Context) (awesomeAPIResponse, error)) {
+	c.sf.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), c.freshTTL+c.staleTTL)
+		defer cancel()
+
+		fresh, err := refresh(ctx)
+		if err != nil {
+			slog.Warn("cache: falha ao atualizar cotação em background", "key", key, "error", err)
+			return nil, err
+		}
+		c.store(key, fresh)
+		slog.Info("cache: cotação atualizada em background", "key", key)
+		return fresh, nil
+	})
+}
+
+func (c *quoteCache) store(key string, v awesomeAPIResponse) {
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: v, fetchedAt: time.Now()}
+	c.mu.Unlock()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func quoteFor(bid string) awesomeAPIResponse {
+	return awesomeAPIResponse{"USDBRL": {Code: "USD", Codein: "BRL", Bid: bid}}
+}
+
+func TestQuoteCache_MissCallsRefreshAndStores(t *testing.T) {
+	c := newQuoteCache(time.Hour, 2*time.Hour)
+
+	var calls int32
+	refresh := func(ctx context.Context) (awesomeAPIResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		return quoteFor("5.00"), nil
+	}
+
+	v, status, err := c.get(context.Background(), "USD-BRL", refresh)
+	if err != nil {
+		t.Fatalf("get() retornou erro inesperado: %v", err)
+	}
+	if status != cacheMiss {
+		t.Errorf("status = %v, want %v", status, cacheMiss)
+	}
+	if v["USDBRL"].Bid != "5.00" {
+		t.Errorf("bid = %q, want %q", v["USDBRL"].Bid, "5.00")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("refresh foi chamado %d vezes, want 1", got)
+	}
+}
+
+func TestQuoteCache_FreshHitDoesNotCallRefresh(t *testing.T) {
+	c := newQuoteCache(time.Hour, 2*time.Hour)
+
+	var calls int32
+	refresh := func(ctx context.Context) (awesomeAPIResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		return quoteFor("5.00"), nil
+	}
+
+	if _, _, err := c.get(context.Background(), "USD-BRL", refresh); err != nil {
+		t.Fatalf("primeira chamada falhou: %v", err)
+	}
+
+	v, status, err := c.get(context.Background(), "USD-BRL", refresh)
+	if err != nil {
+		t.Fatalf("get() retornou erro inesperado: %v", err)
+	}
+	if status != cacheHit {
+		t.Errorf("status = %v, want %v", status, cacheHit)
+	}
+	if v["USDBRL"].Bid != "5.00" {
+		t.Errorf("bid = %q, want %q", v["USDBRL"].Bid, "5.00")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("refresh foi chamado %d vezes num HIT fresco, want 1 (só a busca inicial)", got)
+	}
+}
+
+// TestQuoteCache_StaleTriggersBackgroundRefreshAndStores cobre a regressão
+// em que a atualização em background renovava apenas o valor em memória,
+// sem dar ao chamador de refresh a chance de persistir a cotação nova — o
+// fix é responsabilidade de quem injeta refresh (fetchAndPersist em
+// main.go), mas aqui garantimos que o cache de fato invoca refresh também
+// no caminho STALE, e não só no MISS.
+func TestQuoteCache_StaleTriggersBackgroundRefreshAndStores(t *testing.T) {
+	c := newQuoteCache(5*time.Millisecond, time.Hour)
+
+	var calls int32
+	bid := "5.00"
+	refresh := func(ctx context.Context) (awesomeAPIResponse, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			return quoteFor(bid), nil
+		}
+		return quoteFor("6.00"), nil
+	}
+
+	if _, status, err := c.get(context.Background(), "USD-BRL", refresh); err != nil || status != cacheMiss {
+		t.Fatalf("primeira chamada: status=%v err=%v, want MISS/nil", status, err)
+	}
+
+	time.Sleep(10 * time.Millisecond) // passa do freshTTL, entra na janela stale
+
+	v, status, err := c.get(context.Background(), "USD-BRL", refresh)
+	if err != nil {
+		t.Fatalf("get() retornou erro inesperado: %v", err)
+	}
+	if status != cacheStale {
+		t.Fatalf("status = %v, want %v", status, cacheStale)
+	}
+	if v["USDBRL"].Bid != "5.00" {
+		t.Errorf("STALE deveria servir o valor antigo imediatamente, obteve bid=%q", v["USDBRL"].Bid)
+	}
+
+	// A atualização em background roda de forma assíncrona; espera ela
+	// terminar e confirma que o refresh (e portanto a persistência que ele
+	// encapsula) foi de fato chamado uma segunda vez.
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&calls) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("refresh foi chamado %d vezes, want 2 (busca inicial + atualização em background)", got)
+	}
+
+	// E confirma que o valor novo devolvido pela atualização em background foi
+	// gravado no cache.
+	var storedBid string
+	for time.Now().Before(deadline) {
+		c.mu.RLock()
+		storedBid = c.entries["USD-BRL"].value["USDBRL"].Bid
+		c.mu.RUnlock()
+		if storedBid == "6.00" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if storedBid != "6.00" {
+		t.Errorf("bid em cache após atualização em background = %q, want %q", storedBid, "6.00")
+	}
+}
+
+func TestQuoteCache_ConcurrentMissCollapsesViaSingleflight(t *testing.T) {
+	c := newQuoteCache(time.Hour, 2*time.Hour)
+
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	refresh := func(ctx context.Context) (awesomeAPIResponse, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return quoteFor("5.00"), nil
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			if _, _, err := c.get(context.Background(), "USD-BRL", refresh); err != nil {
+				t.Errorf("get() retornou erro inesperado: %v", err)
+			}
+		}()
+	}
+
+	<-started
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("refresh foi chamado %d vezes para %d chamadas concorrentes em MISS, want 1 (singleflight)", got, n)
+	}
+}
+
+// TestQuoteCache_SyncRefreshFailureServesStaleIfAvailable garante que, uma
+// vez expirado além de staleTTL, uma falha na atualização síncrona ainda
+// devolve o último valor conhecido (em vez de propagar o erro) quando há
+// um valor em cache para cair de volta.
+func TestQuoteCache_SyncRefreshFailureServesStaleIfAvailable(t *testing.T) {
+	c := newQuoteCache(2*time.Millisecond, 4*time.Millisecond)
+
+	var calls int32
+	refresh := func(ctx context.Context) (awesomeAPIResponse, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return quoteFor("5.00"), nil
+		}
+		return nil, errors.New("upstream indisponível")
+	}
+
+	if _, _, err := c.get(context.Background(), "USD-BRL", refresh); err != nil {
+		t.Fatalf("primeira chamada falhou: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond) // passa de freshTTL e staleTTL
+
+	v, status, err := c.get(context.Background(), "USD-BRL", refresh)
+	if err != nil {
+		t.Fatalf("get() deveria cair de volta ao valor em cache, retornou erro: %v", err)
+	}
+	if status != cacheStale {
+		t.Errorf("status = %v, want %v", status, cacheStale)
+	}
+	if v["USDBRL"].Bid != "5.00" {
+		t.Errorf("bid = %q, want o valor em cache anterior %q", v["USDBRL"].Bid, "5.00")
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// retryPolicy controla quantas tentativas são feitas contra a API externa e
+// com que espaçamento, antes de desistir e propagar o erro ao chamador.
+type retryPolicy struct {
+	maxAttempts       int
+	initialDelay      time.Duration
+	backoffFactor     float64
+	jitter            time.Duration
+	perAttemptTimeout time.Duration
+}
+
+func (p retryPolicy) delayFor(attempt int) time.Duration {
+	delay := p.initialDelay
+	for i := 1; i < attempt; i++ {
+		delay = time.Duration(float64(delay) * p.backoffFactor)
+	}
+	if p.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.jitter) + 1))
+	}
+	return delay
+}
+
+// circuitState representa as três fases clássicas de um circuit breaker.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker abre após `failureThreshold` falhas consecutivas e permanece
+// aberto por `openDuration`, após o que libera uma única sonda (half-open)
+// para decidir se volta a fechar ou reabre.
+type circuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(failureThreshold int, openDuration time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		state:            circuitClosed,
+	}
+}
+
+var errCircuitOpen = errors.New("circuit breaker aberto: chamada à API externa suspensa")
+
+// allow decide se uma chamada pode prosseguir, liberando no máximo uma sonda
+// por ciclo quando o breaker está em half-open.
+func (c *circuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case circuitClosed:
+		return true
+	case circuitOpen:
+		if time.Since(c.openedAt) < c.openDuration {
+			return false
+		}
+		c.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *circuitBreaker) recordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures = 0
+	if c.state != circuitClosed {
+		slog.Info("circuit breaker: sonda bem-sucedida, fechando circuito")
+	}
+	c.state = circuitClosed
+}
+
+func (c *circuitBreaker) recordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == circuitHalfOpen {
+		slog.Warn("circuit breaker: sonda falhou, reabrindo circuito", "open_duration", c.openDuration)
+		c.state = circuitOpen
+		c.openedAt = time.Now()
+		return
+	}
+
+	c.failures++
+	if c.failures >= c.failureThreshold {
+		slog.Warn("circuit breaker: abrindo circuito", "failures", c.failures, "open_duration", c.openDuration)
+		c.state = circuitOpen
+		c.openedAt = time.Now()
+	}
+}
+
+// fetcher executa a chamada à API externa com retry e backoff exponencial,
+// protegida por um circuit breaker. O deadline do contexto recebido sempre
+// prevalece: uma vez vencido, nenhuma nova tentativa é iniciada.
+type fetcher struct {
+	client  *http.Client
+	policy  retryPolicy
+	breaker *circuitBreaker
+}
+
+func newFetcher(client *http.Client, policy retryPolicy, breaker *circuitBreaker) *fetcher {
+	return &fetcher{client: client, policy: policy, breaker: breaker}
+}
+
+// isRetryable reporta se o erro ou status retornado pela API externa
+// justifica uma nova tentativa: erros de rede, timeout da própria tentativa,
+// 5xx e 429. O deadline de ctx (o contexto original, não o derivado por
+// tentativa) é quem decide quando parar: se ele ainda está de pé, o estouro
+// do timeout por tentativa é só mais uma falha transitória a ser retentada.
+func isRetryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	if resp == nil {
+		return false
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// retryAfter extrai o atraso sugerido pelo header Retry-After, em segundos,
+// quando presente e numérico.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs) * time.Second, true
+}
+
+// do executa req com retry/backoff e circuit breaker, respeitando o deadline
+// de ctx (que é o do request original, não ampliado pela política de retry).
+func (f *fetcher) do(ctx context.Context, newReq func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	if !f.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= f.policy.maxAttempts; attempt++ {
+		attemptCtx := ctx
+		if f.policy.perAttemptTimeout > 0 {
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(ctx, f.policy.perAttemptTimeout)
+			defer cancel()
+		}
+
+		req, err := newReq(attemptCtx)
+		if err != nil {
+			f.breaker.recordFailure()
+			return nil, err
+		}
+
+		resp, err := f.client.Do(req)
+
+		if err == nil && resp.StatusCode < 300 {
+			f.breaker.recordSuccess()
+			return resp, nil
+		}
+
+		if !isRetryable(ctx, resp, err) {
+			// Mesmo sem retentativa, o breaker precisa saber que esta tentativa
+			// falhou: caso contrário uma sonda half-open cujo ctx externo já
+			// expirou (o caso comum quando apiTimeout é consumido pelas
+			// tentativas anteriores) nunca sai de half-open, travando o
+			// circuito aberto para sempre.
+			f.breaker.recordFailure()
+			return resp, err
+		}
+
+		lastErr = err
+		if resp != nil {
+			lastErr = errHTTPStatus(resp.StatusCode)
+			resp.Body.Close()
+		}
+		f.breaker.recordFailure()
+
+		if attempt == f.policy.maxAttempts {
+			break
+		}
+
+		delay := f.policy.delayFor(attempt)
+		if d, ok := retryAfter(resp); ok {
+			delay = d
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return nil, lastErr
+}
+
+type errHTTPStatus int
+
+func (e errHTTPStatus) Error() string {
+	return "API externa retornou status " + strconv.Itoa(int(e))
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	liveCtx, cancelLive := context.WithCancel(context.Background())
+	defer cancelLive()
+
+	expiredCtx, cancelExpired := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancelExpired()
+	<-expiredCtx.Done()
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		resp *http.Response
+		err  error
+		want bool
+	}{
+		{"5xx é retryable", liveCtx, &http.Response{StatusCode: http.StatusInternalServerError}, nil, true},
+		{"429 é retryable", liveCtx, &http.Response{StatusCode: http.StatusTooManyRequests}, nil, true},
+		{"404 não é retryable", liveCtx, &http.Response{StatusCode: http.StatusNotFound}, nil, false},
+		{"timeout da tentativa com ctx externo vivo é retryable", liveCtx, nil, context.DeadlineExceeded, true},
+		{"erro de rede com ctx externo vivo é retryable", liveCtx, nil, errors.New("connection refused"), true},
+		{"ctx externo expirado nunca é retryable", expiredCtx, nil, context.DeadlineExceeded, false},
+		{"resp nil sem erro não é retryable", liveCtx, nil, nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.ctx, tc.resp, tc.err); got != tc.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestFetcherDo_RetriesOnPerAttemptTimeout cobre a regressão em que um
+// timeout por tentativa (servidor lento só na primeira chamada) era
+// indistinguível do deadline do ctx original, abortando a busca sem
+// nenhuma retentativa.
+func TestFetcherDo_RetriesOnPerAttemptTimeout(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	f := newFetcher(&http.Client{}, retryPolicy{
+		maxAttempts:       2,
+		initialDelay:      time.Millisecond,
+		backoffFactor:     1,
+		perAttemptTimeout: 10 * time.Millisecond,
+	}, newCircuitBreaker(5, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	resp, err := f.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("do() retornou erro inesperado: %v", err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("esperava 2 chamadas (1 timeout + 1 retentativa bem-sucedida), obteve %d", got)
+	}
+}
+
+// TestFetcherDo_StopsWhenOuterContextExpires garante que, quando é o
+// deadline do ctx original que estoura (não o da tentativa), nenhuma nova
+// tentativa é feita.
+func TestFetcherDo_StopsWhenOuterContextExpires(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	f := newFetcher(&http.Client{}, retryPolicy{
+		maxAttempts:       3,
+		initialDelay:      time.Millisecond,
+		backoffFactor:     1,
+		perAttemptTimeout: time.Second,
+	}, newCircuitBreaker(5, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := f.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	})
+	if err == nil {
+		t.Fatal("esperava erro de timeout do ctx externo, obteve nil")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("esperava exatamente 1 chamada (sem retentativa), obteve %d", got)
+	}
+}
+
+func TestCircuitBreaker_OpensAfterThresholdAndHalfOpens(t *testing.T) {
+	cb := newCircuitBreaker(2, 20*time.Millisecond)
+
+	if !cb.allow() {
+		t.Fatal("breaker fechado deveria permitir chamadas")
+	}
+
+	cb.recordFailure()
+	if !cb.allow() {
+		t.Fatal("breaker ainda não deveria abrir antes do threshold")
+	}
+
+	cb.recordFailure()
+	if cb.allow() {
+		t.Fatal("breaker deveria abrir após atingir o threshold de falhas")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !cb.allow() {
+		t.Fatal("breaker deveria liberar uma sonda (half-open) após openDuration")
+	}
+	if cb.allow() {
+		t.Fatal("breaker em half-open só deve liberar uma sonda por ciclo")
+	}
+
+	cb.recordSuccess()
+	if !cb.allow() {
+		t.Fatal("breaker deveria fechar após sonda bem-sucedida")
+	}
+}
+
+func TestCircuitBreaker_ReopensOnFailedProbe(t *testing.T) {
+	cb := newCircuitBreaker(1, 10*time.Millisecond)
+
+	cb.recordFailure()
+	if cb.allow() {
+		t.Fatal("breaker deveria abrir após uma única falha (threshold=1)")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !cb.allow() {
+		t.Fatal("breaker deveria liberar a sonda half-open")
+	}
+
+	cb.recordFailure()
+	if cb.allow() {
+		t.Fatal("breaker deveria reabrir imediatamente após sonda half-open falhar")
+	}
+}
+
+// TestFetcherDo_HalfOpenProbeWithExpiredCtxDoesNotStickForever cobre a
+// regressão em que uma sonda half-open cujo ctx externo já estava expirado
+// (o caso comum quando apiTimeout é consumido pelas tentativas anteriores)
+// fazia isRetryable recusar a retentativa sem nunca notificar o breaker,
+// deixando-o travado em half-open (e, portanto, recusando toda chamada
+// futura) para sempre.
+func TestFetcherDo_HalfOpenProbeWithExpiredCtxDoesNotStickForever(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	const openDuration = 20 * time.Millisecond
+	breaker := newCircuitBreaker(1, openDuration)
+	f := newFetcher(&http.Client{}, retryPolicy{maxAttempts: 1}, breaker)
+
+	newReq := func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	}
+
+	// Primeira chamada falha e abre o circuito (threshold=1).
+	if _, err := f.do(context.Background(), newReq); err == nil {
+		t.Fatal("esperava falha na primeira chamada")
+	}
+	if breaker.allow() {
+		t.Fatal("breaker deveria estar aberto logo após a falha")
+	}
+
+	time.Sleep(openDuration + 5*time.Millisecond)
+
+	// A sonda half-open chega com o ctx externo já expirado — reproduz o
+	// apiTimeout inteiro consumido pelas tentativas anteriores em
+	// handleCotacao.
+	expiredCtx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Millisecond))
+	defer cancel()
+
+	if _, err := f.do(expiredCtx, newReq); err == nil {
+		t.Fatal("esperava erro na sonda com ctx já expirado")
+	}
+
+	// O breaker não pode ficar travado em half-open: deve ter reaberto com um
+	// novo cooldown.
+	time.Sleep(2 * time.Millisecond)
+	if breaker.allow() {
+		t.Fatal("breaker deveria ter reaberto (não permanecido half-open) após a sonda falhar")
+	}
+
+	time.Sleep(openDuration + 5*time.Millisecond)
+	if !breaker.allow() {
+		t.Fatal("breaker deveria liberar uma nova sonda half-open após o novo cooldown, não ficar travado para sempre")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+// quoteDTO é a representação de uma cotação exposta pela API HTTP, separada
+// do modelo de persistência (Quote) para não vazar o ID interno do store.
+type quoteDTO struct {
+	Code   string    `json:"code"`
+	Codein string    `json:"codein"`
+	Bid    string    `json:"bid"`
+	Ts     time.Time `json:"ts"`
+}
+
+func toQuoteDTO(q Quote) quoteDTO {
+	return quoteDTO{Code: q.Code, Codein: q.Codein, Bid: q.Bid, Ts: q.Ts}
+}
+
+type quotesListResponse struct {
+	Quotes        []quoteDTO `json:"quotes"`
+	NextPageToken string     `json:"nextPageToken,omitempty"`
+}
+
+// encodePageToken e decodePageToken tratam o cursor de paginação como opaco
+// para o cliente: internamente é apenas o ID da última cotação retornada.
+func encodePageToken(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+}
+
+func decodePageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("pageToken inválido: %w", err)
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("pageToken inválido: %w", err)
+	}
+	return id, nil
+}
+
+// splitPair valida e decompõe um par no formato "USD-BRL" em seus códigos
+// (code, codein), no mesmo formato aceito por ?pair= em /cotacao.
+func splitPair(pair string) (code, codein string, err error) {
+	parts := strings.SplitN(strings.ToUpper(strings.TrimSpace(pair)), "-", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("parâmetro pair inválido, use o formato CODE-CODEIN, ex.: USD-BRL")
+	}
+	return parts[0], parts[1], nil
+}
+
+// handleQuotesLatest atende GET /quotes/latest, devolvendo a cotação mais
+// recentemente persistida. O parâmetro opcional ?pair= restringe a um par
+// específico; na ausência dele, devolve a mais recente entre todos os pares.
+func (s *server) handleQuotesLatest(w http.ResponseWriter, r *http.Request) {
+	var code, codein string
+	if raw := r.URL.Query().Get("pair"); raw != "" {
+		var err error
+		code, codein, err = splitPair(raw)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	q, err := s.store.Latest(r.Context(), code, codein)
+	if err != nil {
+		if errors.Is(err, errNoQuotes) {
+			http.Error(w, "nenhuma cotação persistida ainda", http.StatusNotFound)
+			return
+		}
+		loggerFromContext(r.Context()).Error("erro ao consultar última cotação", "error", err)
+		http.Error(w, "erro ao consultar cotação", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(toQuoteDTO(q))
+}
+
+// handleQuotesList atende GET /quotes, com filtros since/until, paginação por
+// cursor (limit/pageToken) e negociação de Content-Type entre JSON e CSV.
+func (s *server) handleQuotesList(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	quotes, err := s.store.List(r.Context(), params)
+	if err != nil {
+		loggerFromContext(r.Context()).Error("erro ao listar cotações", "error", err)
+		http.Error(w, "erro ao listar cotações", http.StatusInternalServerError)
+		return
+	}
+
+	var nextPageToken string
+	if len(quotes) == params.Limit {
+		nextPageToken = encodePageToken(quotes[len(quotes)-1].ID)
+	}
+
+	if wantsCSV(r) {
+		writeQuotesCSV(w, quotes)
+		return
+	}
+
+	dtos := make([]quoteDTO, 0, len(quotes))
+	for _, q := range quotes {
+		dtos = append(dtos, toQuoteDTO(q))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(quotesListResponse{Quotes: dtos, NextPageToken: nextPageToken})
+}
+
+func parseListParams(r *http.Request) (ListParams, error) {
+	q := r.URL.Query()
+
+	params := ListParams{
+		Since: time.Unix(0, 0).UTC(),
+		Until: time.Now().UTC(),
+		Limit: defaultListLimit,
+	}
+
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return ListParams{}, fmt.Errorf("parâmetro since inválido, use RFC3339: %w", err)
+		}
+		params.Since = t
+	}
+
+	if v := q.Get("until"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return ListParams{}, fmt.Errorf("parâmetro until inválido, use RFC3339: %w", err)
+		}
+		params.Until = t
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return ListParams{}, fmt.Errorf("parâmetro limit inválido, deve ser inteiro positivo")
+		}
+		if n > maxListLimit {
+			n = maxListLimit
+		}
+		params.Limit = n
+	}
+
+	if v := q.Get("pageToken"); v != "" {
+		afterID, err := decodePageToken(v)
+		if err != nil {
+			return ListParams{}, err
+		}
+		params.AfterID = afterID
+	}
+
+	if v := q.Get("pair"); v != "" {
+		code, codein, err := splitPair(v)
+		if err != nil {
+			return ListParams{}, err
+		}
+		params.Code = code
+		params.Codein = codein
+	}
+
+	return params, nil
+}
+
+// wantsCSV decide o formato de resposta a partir do parâmetro `format` ou,
+// na ausência dele, do header Accept.
+func wantsCSV(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return strings.EqualFold(f, "csv")
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+func writeQuotesCSV(w http.ResponseWriter, quotes []Quote) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"code", "codein", "bid", "ts"})
+	for _, q := range quotes {
+		_ = cw.Write([]string{q.Code, q.Codein, q.Bid, q.Ts.Format(time.RFC3339)})
+	}
+	cw.Flush()
+}
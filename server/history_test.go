@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodePageToken_RoundTrips(t *testing.T) {
+	token := encodePageToken(42)
+	got, err := decodePageToken(token)
+	if err != nil {
+		t.Fatalf("decodePageToken(%q): %v", token, err)
+	}
+	if got != 42 {
+		t.Errorf("decodePageToken(%q) = %d, want 42", token, got)
+	}
+}
+
+func TestDecodePageToken_EmptyIsZeroWithoutError(t *testing.T) {
+	got, err := decodePageToken("")
+	if err != nil || got != 0 {
+		t.Errorf("decodePageToken(\"\") = (%d, %v), want (0, nil)", got, err)
+	}
+}
+
+func TestDecodePageToken_InvalidReturnsError(t *testing.T) {
+	if _, err := decodePageToken("not-base64!!"); err == nil {
+		t.Error("decodePageToken com token inválido deveria falhar")
+	}
+}
+
+func TestSplitPair(t *testing.T) {
+	cases := []struct {
+		name       string
+		pair       string
+		wantCode   string
+		wantCodein string
+		wantErr    bool
+	}{
+		{"par válido", "USD-BRL", "USD", "BRL", false},
+		{"normaliza caixa e espaços", " usd-brl ", "USD", "BRL", false},
+		{"sem hífen", "USDBRL", "", "", true},
+		{"lado vazio", "USD-", "", "", true},
+		{"vazio", "", "", "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, codein, err := splitPair(tc.pair)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("splitPair(%q) deveria falhar", tc.pair)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitPair(%q): %v", tc.pair, err)
+			}
+			if code != tc.wantCode || codein != tc.wantCodein {
+				t.Errorf("splitPair(%q) = (%q, %q), want (%q, %q)", tc.pair, code, codein, tc.wantCode, tc.wantCodein)
+			}
+		})
+	}
+}
+
+func TestParseListParams_Defaults(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/quotes", nil)
+	params, err := parseListParams(r)
+	if err != nil {
+		t.Fatalf("parseListParams: %v", err)
+	}
+	if params.Limit != defaultListLimit {
+		t.Errorf("Limit = %d, want %d", params.Limit, defaultListLimit)
+	}
+	if params.Code != "" || params.Codein != "" {
+		t.Errorf("Code/Codein deveriam ficar vazios sem ?pair, obteve %q/%q", params.Code, params.Codein)
+	}
+	if params.AfterID != 0 {
+		t.Errorf("AfterID = %d, want 0", params.AfterID)
+	}
+}
+
+func TestParseListParams_LimitIsClampedToMax(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/quotes?limit=100000", nil)
+	params, err := parseListParams(r)
+	if err != nil {
+		t.Fatalf("parseListParams: %v", err)
+	}
+	if params.Limit != maxListLimit {
+		t.Errorf("Limit = %d, want %d (clampeado)", params.Limit, maxListLimit)
+	}
+}
+
+func TestParseListParams_InvalidLimitIsRejected(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/quotes?limit=0", nil)
+	if _, err := parseListParams(r); err == nil {
+		t.Error("limit=0 deveria ser rejeitado")
+	}
+}
+
+func TestParseListParams_InvalidSinceUntilAreRejected(t *testing.T) {
+	for _, q := range []string{"since=not-a-date", "until=not-a-date"} {
+		r := httptest.NewRequest(http.MethodGet, "/quotes?"+q, nil)
+		if _, err := parseListParams(r); err == nil {
+			t.Errorf("?%s deveria ser rejeitado", q)
+		}
+	}
+}
+
+func TestParseListParams_PairPopulatesCodeAndCodein(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/quotes?pair=EUR-BRL", nil)
+	params, err := parseListParams(r)
+	if err != nil {
+		t.Fatalf("parseListParams: %v", err)
+	}
+	if params.Code != "EUR" || params.Codein != "BRL" {
+		t.Errorf("Code/Codein = %q/%q, want EUR/BRL", params.Code, params.Codein)
+	}
+}
+
+func TestParseListParams_InvalidPairIsRejected(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/quotes?pair=invalido", nil)
+	if _, err := parseListParams(r); err == nil {
+		t.Error("?pair=invalido deveria ser rejeitado")
+	}
+}
+
+func TestParseListParams_PageTokenPopulatesAfterID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/quotes?pageToken="+encodePageToken(7), nil)
+	params, err := parseListParams(r)
+	if err != nil {
+		t.Fatalf("parseListParams: %v", err)
+	}
+	if params.AfterID != 7 {
+		t.Errorf("AfterID = %d, want 7", params.AfterID)
+	}
+}
+
+func TestWantsCSV(t *testing.T) {
+	cases := []struct {
+		name   string
+		url    string
+		accept string
+		want   bool
+	}{
+		{"format=csv vence", "/quotes?format=csv", "", true},
+		{"format=json explícito", "/quotes?format=json", "text/csv", false},
+		{"Accept text/csv sem format", "/quotes", "text/csv", true},
+		{"sem nada", "/quotes", "", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			if got := wantsCSV(r); got != tc.want {
+				t.Errorf("wantsCSV(%s, Accept=%q) = %v, want %v", tc.url, tc.accept, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteQuotesCSV(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	rec := httptest.NewRecorder()
+	writeQuotesCSV(rec, []Quote{{ID: 1, Code: "USD", Codein: "BRL", Bid: "5.00", Ts: ts}})
+
+	if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "code,codein,bid,ts") {
+		t.Errorf("corpo CSV sem cabeçalho esperado: %q", body)
+	}
+	if !strings.Contains(body, "USD,BRL,5.00,"+ts.Format(time.RFC3339)) {
+		t.Errorf("corpo CSV sem linha esperada: %q", body)
+	}
+}
+
+// stubQuoteStore é um QuoteStore mínimo controlado pelo teste, usado para
+// exercitar os handlers HTTP sem depender de um backend real.
+type stubQuoteStore struct {
+	latestQuote  Quote
+	latestErr    error
+	latestCode   string
+	latestCodein string
+
+	listQuotes []Quote
+	listErr    error
+}
+
+func (s *stubQuoteStore) Insert(ctx context.Context, q Quote) error { return nil }
+
+func (s *stubQuoteStore) Latest(ctx context.Context, code, codein string) (Quote, error) {
+	s.latestCode, s.latestCodein = code, codein
+	return s.latestQuote, s.latestErr
+}
+
+func (s *stubQuoteStore) List(ctx context.Context, params ListParams) ([]Quote, error) {
+	return s.listQuotes, s.listErr
+}
+
+func (s *stubQuoteStore) Close() error { return nil }
+
+func TestHandleQuotesLatest_NoPairPassesEmptyFilter(t *testing.T) {
+	store := &stubQuoteStore{latestQuote: Quote{ID: 1, Code: "USD", Codein: "BRL", Bid: "5.00"}}
+	srv := &server{store: store}
+
+	r := httptest.NewRequest(http.MethodGet, "/quotes/latest", nil)
+	rec := httptest.NewRecorder()
+	srv.handleQuotesLatest(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if store.latestCode != "" || store.latestCodein != "" {
+		t.Errorf("Latest foi chamado com code=%q codein=%q sem ?pair, want vazio", store.latestCode, store.latestCodein)
+	}
+}
+
+func TestHandleQuotesLatest_PairIsForwardedToStore(t *testing.T) {
+	store := &stubQuoteStore{latestQuote: Quote{ID: 1, Code: "EUR", Codein: "BRL", Bid: "6.00"}}
+	srv := &server{store: store}
+
+	r := httptest.NewRequest(http.MethodGet, "/quotes/latest?pair=EUR-BRL", nil)
+	rec := httptest.NewRecorder()
+	srv.handleQuotesLatest(rec, r)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if store.latestCode != "EUR" || store.latestCodein != "BRL" {
+		t.Errorf("Latest chamado com code=%q codein=%q, want EUR/BRL", store.latestCode, store.latestCodein)
+	}
+}
+
+func TestHandleQuotesLatest_InvalidPairIsBadRequest(t *testing.T) {
+	srv := &server{store: &stubQuoteStore{}}
+
+	r := httptest.NewRequest(http.MethodGet, "/quotes/latest?pair=invalido", nil)
+	rec := httptest.NewRecorder()
+	srv.handleQuotesLatest(rec, r)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleQuotesLatest_NoQuotesIs404(t *testing.T) {
+	srv := &server{store: &stubQuoteStore{latestErr: errNoQuotes}}
+
+	r := httptest.NewRequest(http.MethodGet, "/quotes/latest", nil)
+	rec := httptest.NewRecorder()
+	srv.handleQuotesLatest(rec, r)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ctxKey int
+
+const requestIDKey ctxKey = iota
+
+// newRequestID gera um identificador curto e suficientemente único para
+// correlacionar os logs de uma mesma requisição.
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// loggerFromContext devolve um logger já anotado com o request_id da
+// requisição em curso.
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	return slog.With("request_id", requestIDFromContext(ctx))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// withRequestLogging atribui um request_id a cada requisição, o propaga pelo
+// contexto e registra método/status/duração ao final, além de alimentar as
+// métricas por endpoint.
+func withRequestLogging(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := withRequestID(r.Context(), newRequestID())
+		r = r.WithContext(ctx)
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next(sw, r)
+
+		dur := time.Since(start)
+		observeHTTPRequest(endpoint, sw.status, dur)
+		loggerFromContext(ctx).Info("requisição atendida",
+			"endpoint", endpoint,
+			"method", r.Method,
+			"status", sw.status,
+			"duration_ms", dur.Milliseconds(),
+		)
+	}
+}
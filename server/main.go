@@ -2,138 +2,381 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
-
-	_ "modernc.org/sqlite"
 )
 
 const (
-	serverAddr       = ":8080"
-	externalAPIURL   = "https://economia.awesomeapi.com.br/json/last/USD-BRL"
-	apiTimeout       = 200 * time.Millisecond
-	dbTimeout        = 10 * time.Millisecond
-	sqliteDSN        = "file:quotes.db?cache=shared&_pragma=busy_timeout(5000)"
-	createTableQuery = `
-CREATE TABLE IF NOT EXISTS quotes (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	code TEXT NOT NULL,
-	codein TEXT NOT NULL,
-	bid TEXT NOT NULL,
-	ts DATETIME NOT NULL
-);`
-	insertQuoteQuery = `INSERT INTO quotes(code, codein, bid, ts) VALUES(?, ?, ?, ?);`
+	serverAddr         = ":8080"
+	externalAPIBaseURL = "https://economia.awesomeapi.com.br/json/last/"
+	apiTimeout         = 200 * time.Millisecond
+
+	// defaultDatabaseURL mantém o comportamento histórico (sqlite local) quando
+	// nem --database-url nem DATABASE_URL são informados.
+	defaultDatabaseURL = "sqlite://quotes.db?cache=shared&_pragma=busy_timeout(5000)"
+
+	// defaultAllowedPairs mantém o comportamento histórico (apenas USD-BRL)
+	// quando nem --allowed-pairs nem ALLOWED_PAIRS são informados.
+	defaultAllowedPairs = "USD-BRL"
+
+	// defaultPair é usado quando a requisição não informa ?pair.
+	defaultPair = "USD-BRL"
 )
 
-type awesomeAPIResponse struct {
-	USDBRL struct {
-		Code   string `json:"code"`
-		Codein string `json:"codein"`
-		Bid    string `json:"bid"`
-		// demais campos ignorados
-	} `json:"USDBRL"`
+// serverConfig é preenchido a partir de flags/variáveis de ambiente em main,
+// permitindo ajustar a política de retry e o circuit breaker sem recompilar.
+type serverConfig struct {
+	maxAttempts     int
+	initialDelay    time.Duration
+	backoffFactor   float64
+	jitter          time.Duration
+	perAttemptTO    time.Duration
+	cbFailThreshold int
+	cbOpenDuration  time.Duration
+	databaseURL     string
+	dbTimeout       time.Duration
+	cacheFreshTTL   time.Duration
+	cacheStaleTTL   time.Duration
+	allowedPairs    string
 }
 
+func loadServerConfig() serverConfig {
+	cfg := serverConfig{
+		maxAttempts:     3,
+		initialDelay:    20 * time.Millisecond,
+		backoffFactor:   2.0,
+		jitter:          10 * time.Millisecond,
+		perAttemptTO:    150 * time.Millisecond,
+		cbFailThreshold: 5,
+		cbOpenDuration:  2 * time.Second,
+		databaseURL:     defaultDatabaseURL,
+		dbTimeout:       200 * time.Millisecond,
+		cacheFreshTTL:   500 * time.Millisecond,
+		cacheStaleTTL:   5 * time.Second,
+		allowedPairs:    defaultAllowedPairs,
+	}
+
+	flag.IntVar(&cfg.maxAttempts, "retry-max-attempts", envInt("RETRY_MAX_ATTEMPTS", cfg.maxAttempts), "número máximo de tentativas contra a API externa")
+	flag.DurationVar(&cfg.initialDelay, "retry-initial-delay", envDuration("RETRY_INITIAL_DELAY", cfg.initialDelay), "atraso inicial antes da primeira retentativa")
+	flag.Float64Var(&cfg.backoffFactor, "retry-backoff-factor", envFloat("RETRY_BACKOFF_FACTOR", cfg.backoffFactor), "fator multiplicativo do backoff exponencial")
+	flag.DurationVar(&cfg.jitter, "retry-jitter", envDuration("RETRY_JITTER", cfg.jitter), "jitter máximo somado a cada atraso de retentativa")
+	flag.DurationVar(&cfg.perAttemptTO, "retry-attempt-timeout", envDuration("RETRY_ATTEMPT_TIMEOUT", cfg.perAttemptTO), "timeout de cada tentativa individual")
+	flag.IntVar(&cfg.cbFailThreshold, "cb-failure-threshold", envInt("CB_FAILURE_THRESHOLD", cfg.cbFailThreshold), "falhas consecutivas até abrir o circuit breaker")
+	flag.DurationVar(&cfg.cbOpenDuration, "cb-open-duration", envDuration("CB_OPEN_DURATION", cfg.cbOpenDuration), "tempo que o circuit breaker permanece aberto antes da sonda half-open")
+	flag.StringVar(&cfg.databaseURL, "database-url", envString("DATABASE_URL", cfg.databaseURL), "URL do backend de persistência (sqlite://, json:// ou postgres://)")
+	flag.DurationVar(&cfg.dbTimeout, "db-timeout", envDuration("DB_TIMEOUT", cfg.dbTimeout), "timeout ao persistir uma cotação no backend de armazenamento")
+	flag.DurationVar(&cfg.cacheFreshTTL, "cache-fresh-ttl", envDuration("CACHE_FRESH_TTL", cfg.cacheFreshTTL), "por quanto tempo a cotação em cache é servida sem revalidar")
+	flag.DurationVar(&cfg.cacheStaleTTL, "cache-stale-ttl", envDuration("CACHE_STALE_TTL", cfg.cacheStaleTTL), "idade máxima, contada desde a busca, até a qual a cotação em cache ainda é servida (com revalidação em background) depois de cache-fresh-ttl")
+	flag.StringVar(&cfg.allowedPairs, "allowed-pairs", envString("ALLOWED_PAIRS", cfg.allowedPairs), "lista de pares de moeda permitidos em ?pair, separados por vírgula (ex.: USD-BRL,EUR-BRL)")
+	flag.Parse()
+
+	return cfg
+}
+
+func envString(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func envFloat(key string, fallback float64) float64 {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// awesomeAPIQuote é o formato de cada entrada da resposta em batch da
+// AwesomeAPI, chaveada por código concatenado (ex.: "USDBRL").
+type awesomeAPIQuote struct {
+	Code   string `json:"code"`
+	Codein string `json:"codein"`
+	Bid    string `json:"bid"`
+	// demais campos ignorados
+}
+
+// awesomeAPIResponse é a resposta do endpoint /last em batch, que devolve um
+// objeto com uma entrada por par solicitado.
+type awesomeAPIResponse map[string]awesomeAPIQuote
+
 type quoteResponse struct {
 	Bid string `json:"bid"`
 }
 
+// parseAllowedPairs normaliza a lista de pares configurada (separada por
+// vírgula) em um conjunto para validação O(1).
+func parseAllowedPairs(raw string) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p != "" {
+			allowed[p] = true
+		}
+	}
+	return allowed
+}
+
+// parsePairs lê o parâmetro ?pair (comma-separated, ex.: "USD-BRL,EUR-BRL"),
+// usando defaultPair na ausência dele, e valida cada par contra a whitelist.
+func parsePairs(r *http.Request, allowed map[string]bool) ([]string, error) {
+	raw := r.URL.Query().Get("pair")
+	if raw == "" {
+		raw = defaultPair
+	}
+
+	seen := make(map[string]bool)
+	pairs := make([]string, 0, 4)
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if !allowed[p] {
+			return nil, fmt.Errorf("par %q não permitido", p)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		pairs = append(pairs, p)
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("nenhum par informado em ?pair")
+	}
+	return pairs, nil
+}
+
+// awesomeAPIKey converte "USD-BRL" na chave usada pela AwesomeAPI na
+// resposta em batch ("USDBRL").
+func awesomeAPIKey(pair string) string {
+	return strings.ReplaceAll(pair, "-", "")
+}
+
+// decodeError identifica, para fins de métricas/logs, falhas ao decodificar
+// a resposta JSON da API externa.
+type decodeError struct{ err error }
+
+func (e *decodeError) Error() string {
+	return fmt.Sprintf("erro ao decodificar resposta da API externa: %v", e.err)
+}
+func (e *decodeError) Unwrap() error { return e.err }
+
 type server struct {
-	db     *sql.DB
-	client *http.Client
+	store        QuoteStore
+	fetcher      *fetcher
+	cache        *quoteCache
+	allowedPairs map[string]bool
+	dbTimeout    time.Duration
 }
 
 func main() {
-	db, err := sql.Open("sqlite", sqliteDSN)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	cfg := loadServerConfig()
+
+	store, err := newQuoteStore(cfg.databaseURL)
 	if err != nil {
-		log.Fatalf("erro ao abrir banco sqlite: %v", err)
+		slog.Error("erro ao inicializar backend de persistência", "error", err)
+		os.Exit(1)
 	}
-	defer db.Close()
-
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
+	defer store.Close()
 
-	if _, err := db.Exec(createTableQuery); err != nil {
-		log.Fatalf("erro ao criar tabela: %v", err)
+	policy := retryPolicy{
+		maxAttempts:       cfg.maxAttempts,
+		initialDelay:      cfg.initialDelay,
+		backoffFactor:     cfg.backoffFactor,
+		jitter:            cfg.jitter,
+		perAttemptTimeout: cfg.perAttemptTO,
 	}
+	breaker := newCircuitBreaker(cfg.cbFailThreshold, cfg.cbOpenDuration)
 
 	s := &server{
-		db:     db,
-		client: &http.Client{},
+		store:        store,
+		fetcher:      newFetcher(&http.Client{}, policy, breaker),
+		cache:        newQuoteCache(cfg.cacheFreshTTL, cfg.cacheStaleTTL),
+		allowedPairs: parseAllowedPairs(cfg.allowedPairs),
+		dbTimeout:    cfg.dbTimeout,
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/cotacao", s.handleCotacao)
+	mux.HandleFunc("/cotacao", withRequestLogging("/cotacao", s.handleCotacao))
+	mux.HandleFunc("/quotes/latest", withRequestLogging("/quotes/latest", s.handleQuotesLatest))
+	mux.HandleFunc("/quotes", withRequestLogging("/quotes", s.handleQuotesList))
+	mux.Handle("/metrics", metricsHandler())
 
-	log.Printf("Servidor rodando em %s:%s ", "http://localhost", serverAddr)
+	slog.Info("servidor rodando", "addr", "http://localhost"+serverAddr)
 	if err := http.ListenAndServe(serverAddr, mux); err != nil {
-		log.Fatalf("erro no servidor HTTP: %v", err)
+		slog.Error("erro no servidor HTTP", "error", err)
+		os.Exit(1)
 	}
 }
 
 func (s *server) handleCotacao(w http.ResponseWriter, r *http.Request) {
-	ctxAPI, cancelAPI := context.WithTimeout(r.Context(), apiTimeout)
-	defer cancelAPI()
+	logger := loggerFromContext(r.Context())
 
-	req, err := http.NewRequestWithContext(ctxAPI, http.MethodGet, externalAPIURL, nil)
+	pairs, err := parsePairs(r, s.allowedPairs)
 	if err != nil {
-		log.Printf("erro ao criar request p/ API externa: %v", err)
-		http.Error(w, "erro interno", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	cacheKey := strings.Join(pairs, ",")
+
+	ctxAPI, cancelAPI := context.WithTimeout(r.Context(), apiTimeout)
+	defer cancelAPI()
 
-	resp, err := s.client.Do(req)
+	apiResp, status, err := s.cache.get(ctxAPI, cacheKey, func(ctx context.Context) (awesomeAPIResponse, error) {
+		return s.fetchAndPersist(ctx, pairs)
+	})
+	cacheResultsTotal.WithLabelValues(string(status)).Inc()
+	w.Header().Set("X-Cache", string(status))
 	if err != nil {
+		if errors.Is(err, errCircuitOpen) {
+			logger.Warn("circuit breaker aberto: requisição rejeitada sem chamar a API externa")
+			http.Error(w, "API externa indisponível no momento", http.StatusServiceUnavailable)
+			return
+		}
 		if errors.Is(ctxAPI.Err(), context.DeadlineExceeded) {
-			log.Printf("timeout ao chamar API externa (>%v): %v", apiTimeout, err)
+			logger.Warn("timeout ao chamar API externa", "timeout", apiTimeout, "error", err)
 			http.Error(w, "timeout na API externa", http.StatusGatewayTimeout)
 			return
 		}
-		log.Printf("erro ao chamar API externa: %v", err)
+		logger.Error("erro ao chamar API externa", "error", err)
 		http.Error(w, "erro ao obter cotação", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("API externa retornou status %d", resp.StatusCode)
-		http.Error(w, "falha na API externa", http.StatusBadGateway)
-		return
+	quotes := make(map[string]quoteResponse, len(pairs))
+	for _, pair := range pairs {
+		rec, ok := apiResp[awesomeAPIKey(pair)]
+		if !ok || rec.Bid == "" {
+			logger.Error("resposta da API sem cotação para o par", "pair", pair)
+			http.Error(w, "cotação indisponível", http.StatusBadGateway)
+			return
+		}
+		quotes[pair] = quoteResponse{Bid: rec.Bid}
 	}
 
-	var apiResp awesomeAPIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		log.Printf("erro ao decodificar resposta da API externa: %v", err)
-		http.Error(w, "erro ao processar cotação", http.StatusBadGateway)
-		return
-	}
+	logger.Info("cotação servida", "cache", string(status), "pairs", cacheKey)
 
-	bid := apiResp.USDBRL.Bid
-	code := apiResp.USDBRL.Code
-	codein := apiResp.USDBRL.Codein
-	if bid == "" {
-		log.Printf("resposta da API sem campo 'bid'")
-		http.Error(w, "cotação indisponível", http.StatusBadGateway)
-		return
-	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(quotes)
+}
 
-	ctxDB, cancelDB := context.WithTimeout(r.Context(), dbTimeout)
+func (s *server) persistQuote(ctx context.Context, logger *slog.Logger, q Quote) {
+	ctxDB, cancelDB := context.WithTimeout(ctx, s.dbTimeout)
 	defer cancelDB()
 
-	_, err = s.db.ExecContext(ctxDB, insertQuoteQuery, code, codein, bid, time.Now().UTC())
+	insertStart := time.Now()
+	err := s.store.Insert(ctxDB, q)
+	dbInsertDuration.Observe(time.Since(insertStart).Seconds())
 	if err != nil {
 		if errors.Is(ctxDB.Err(), context.DeadlineExceeded) {
-			log.Printf("timeout ao persistir no banco (>%v): %v", dbTimeout, err)
+			dbTimeoutsTotal.Inc()
+			logger.Warn("timeout ao persistir no banco", "timeout", s.dbTimeout, "error", err)
 		} else {
-			log.Printf("erro ao persistir no banco: %v", err)
+			logger.Error("erro ao persistir no banco", "error", err)
 		}
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(quoteResponse{Bid: bid})
+// fetchAndPersist busca as cotações dos pares informados e persiste uma
+// linha por par assim que chegam. É a função de revalidação passada ao
+// cache, usada tanto no caminho síncrono (MISS) quanto na atualização em
+// background (STALE) — e portanto o único lugar onde dados novos de fato
+// chegam da API externa, garantindo que ambos os caminhos alimentem o
+// histórico em /quotes. Usa context.Background() para a persistência: ela
+// não deve ser cancelada nem pelo fim da requisição original (MISS) nem
+// pelo encerramento do ctx efêmero da atualização em background (STALE).
+func (s *server) fetchAndPersist(ctx context.Context, pairs []string) (awesomeAPIResponse, error) {
+	apiResp, err := s.fetchQuotes(ctx, pairs)
+	if err != nil {
+		return apiResp, err
+	}
+
+	logger := slog.Default()
+	now := time.Now().UTC()
+	for _, pair := range pairs {
+		rec, ok := apiResp[awesomeAPIKey(pair)]
+		if !ok || rec.Bid == "" {
+			logger.Error("resposta da API sem cotação para o par, não persistindo", "pair", pair)
+			continue
+		}
+		s.persistQuote(context.Background(), logger, Quote{Code: rec.Code, Codein: rec.Codein, Bid: rec.Bid, Ts: now})
+	}
+
+	return apiResp, nil
+}
+
+// fetchQuotes busca e decodifica as cotações dos pares informados através do
+// fetcher (retry + circuit breaker), em uma única chamada em batch à API
+// externa.
+func (s *server) fetchQuotes(ctx context.Context, pairs []string) (awesomeAPIResponse, error) {
+	start := time.Now()
+	apiResp, err := s.doFetchQuotes(ctx, pairs)
+	upstreamFetchDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		upstreamErrorsTotal.WithLabelValues(classifyUpstreamError(err)).Inc()
+	}
+	return apiResp, err
+}
+
+func (s *server) doFetchQuotes(ctx context.Context, pairs []string) (awesomeAPIResponse, error) {
+	url := externalAPIBaseURL + strings.Join(pairs, ",")
+
+	resp, err := s.fetcher.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errHTTPStatus(resp.StatusCode)
+	}
+
+	var apiResp awesomeAPIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, &decodeError{err: err}
+	}
+	return apiResp, nil
 }
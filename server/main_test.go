@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAllowedPairs(t *testing.T) {
+	got := parseAllowedPairs(" usd-brl ,EUR-BRL,, usd-brl")
+	want := map[string]bool{"USD-BRL": true, "EUR-BRL": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAllowedPairs() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePairs_DefaultsWhenNoQueryParam(t *testing.T) {
+	allowed := parseAllowedPairs(defaultPair)
+	r := httptest.NewRequest(http.MethodGet, "/cotacao", nil)
+
+	pairs, err := parsePairs(r, allowed)
+	if err != nil {
+		t.Fatalf("parsePairs: %v", err)
+	}
+	if len(pairs) != 1 || pairs[0] != defaultPair {
+		t.Errorf("parsePairs() = %v, want [%s]", pairs, defaultPair)
+	}
+}
+
+func TestParsePairs_SplitsDedupesAndNormalizesCase(t *testing.T) {
+	allowed := parseAllowedPairs("USD-BRL,EUR-BRL")
+	r := httptest.NewRequest(http.MethodGet, "/cotacao?pair=usd-brl,EUR-BRL,usd-brl", nil)
+
+	pairs, err := parsePairs(r, allowed)
+	if err != nil {
+		t.Fatalf("parsePairs: %v", err)
+	}
+	want := []string{"USD-BRL", "EUR-BRL"}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("parsePairs() = %v, want %v", pairs, want)
+	}
+}
+
+func TestParsePairs_RejectsPairNotInWhitelist(t *testing.T) {
+	allowed := parseAllowedPairs("USD-BRL")
+	r := httptest.NewRequest(http.MethodGet, "/cotacao?pair=JPY-BRL", nil)
+
+	if _, err := parsePairs(r, allowed); err == nil {
+		t.Error("parsePairs deveria rejeitar um par fora da whitelist")
+	}
+}
+
+func TestParsePairs_RejectsEmptyResult(t *testing.T) {
+	allowed := parseAllowedPairs("USD-BRL")
+	r := httptest.NewRequest(http.MethodGet, "/cotacao?pair=%20,%20,", nil)
+
+	if _, err := parsePairs(r, allowed); err == nil {
+		t.Error("parsePairs deveria rejeitar quando nenhum par sobra após normalizar")
+	}
+}
+
+func TestAwesomeAPIKey(t *testing.T) {
+	if got := awesomeAPIKey("USD-BRL"); got != "USDBRL" {
+		t.Errorf("awesomeAPIKey(USD-BRL) = %q, want USDBRL", got)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Métricas Prometheus expostas em /metrics. Os nomes seguem o prefixo do
+// módulo para evitar colisão com outras aplicações no mesmo scrape target.
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_server_api_http_requests_total",
+		Help: "Total de requisições HTTP atendidas, por endpoint e status.",
+	}, []string{"endpoint", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "client_server_api_http_request_duration_seconds",
+		Help:    "Duração das requisições HTTP atendidas, por endpoint e status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "status"})
+
+	upstreamFetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "client_server_api_upstream_fetch_duration_seconds",
+		Help:    "Duração das chamadas à API externa (AwesomeAPI), incluindo retries.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	upstreamErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_server_api_upstream_errors_total",
+		Help: "Total de falhas ao chamar a API externa, por classe de erro (circuit_open, timeout, 5xx, http_error, decode, other).",
+	}, []string{"class"})
+
+	dbInsertDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "client_server_api_db_insert_duration_seconds",
+		Help:    "Duração das gravações de cotações no backend de persistência.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	dbTimeoutsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "client_server_api_db_timeouts_total",
+		Help: "Total de timeouts ao persistir cotações no backend de persistência.",
+	})
+
+	cacheResultsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_server_api_cache_results_total",
+		Help: "Total de respostas do cache de cotação, por status (HIT, STALE, MISS).",
+	}, []string{"status"})
+)
+
+func metricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+func observeHTTPRequest(endpoint string, status int, dur time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	httpRequestsTotal.WithLabelValues(endpoint, statusLabel).Inc()
+	httpRequestDuration.WithLabelValues(endpoint, statusLabel).Observe(dur.Seconds())
+}
+
+// classifyUpstreamError mapeia um erro de upstream para a label de classe
+// usada em upstreamErrorsTotal.
+func classifyUpstreamError(err error) string {
+	if errors.Is(err, errCircuitOpen) {
+		return "circuit_open"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	var status errHTTPStatus
+	if errors.As(err, &status) {
+		if status >= 500 {
+			return "5xx"
+		}
+		return "http_error"
+	}
+	if errors.As(err, new(*decodeError)) {
+		return "decode"
+	}
+	return "other"
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// Quote é o registro persistido a cada cotação obtida com sucesso da API
+// externa, independentemente do backend de armazenamento usado.
+type Quote struct {
+	ID     int64
+	Code   string
+	Codein string
+	Bid    string
+	Ts     time.Time
+}
+
+// ListParams filtra e pagina o histórico de cotações retornado por
+// QuoteStore.List. AfterID implementa a paginação por cursor: quando
+// diferente de zero, apenas cotações com ID maior são retornadas. Code e
+// Codein, quando não vazios, restringem o resultado a um par específico
+// (ex.: "USD"/"BRL"); vazios não filtram por par.
+type ListParams struct {
+	Since   time.Time
+	Until   time.Time
+	AfterID int64
+	Limit   int
+	Code    string
+	Codein  string
+}
+
+// QuoteStore abstrai o backend de persistência das cotações, permitindo
+// trocar sqlite, um arquivo JSON ou postgres sem tocar em handleCotacao.
+// Em Latest, code e codein vazios não filtram por par (devolvem a cotação
+// mais recente entre todos os pares registrados).
+type QuoteStore interface {
+	Insert(ctx context.Context, q Quote) error
+	Latest(ctx context.Context, code, codein string) (Quote, error)
+	List(ctx context.Context, params ListParams) ([]Quote, error)
+	Close() error
+}
+
+var errNoQuotes = fmt.Errorf("nenhuma cotação persistida ainda")
+
+// newQuoteStore seleciona a implementação de QuoteStore a partir do esquema
+// de databaseURL: sqlite://, json:// ou postgres:// (postgresql:// também é
+// aceito). Na ausência de esquema reconhecido, retorna erro.
+func newQuoteStore(databaseURL string) (QuoteStore, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao interpretar database-url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "sqlite":
+		return newSQLiteStore(sqliteDSNFromURL(u))
+	case "json":
+		return newJSONStore(jsonPathFromURL(u))
+	case "postgres", "postgresql":
+		return newPostgresStore(databaseURL)
+	default:
+		return nil, fmt.Errorf("esquema de database-url não suportado: %q", u.Scheme)
+	}
+}
+
+// sqliteDSNFromURL converte sqlite://quotes.db?... (ou sqlite:quotes.db?...)
+// no DSN file:quotes.db?... aceito pelo driver modernc.org/sqlite,
+// preservando query string (pragmas etc).
+func sqliteDSNFromURL(u *url.URL) string {
+	path := u.Opaque
+	if path == "" {
+		path = u.Host + u.Path
+	}
+	if u.RawQuery != "" {
+		return "file:" + path + "?" + u.RawQuery
+	}
+	return "file:" + path
+}
+
+func jsonPathFromURL(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
+
+// --- sqlite ---
+
+const (
+	createTableQuery = `
+CREATE TABLE IF NOT EXISTS quotes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	code TEXT NOT NULL,
+	codein TEXT NOT NULL,
+	bid TEXT NOT NULL,
+	ts DATETIME NOT NULL
+);`
+	insertQuoteQuery       = `INSERT INTO quotes(code, codein, bid, ts) VALUES(?, ?, ?, ?);`
+	latestQuoteQuery       = `SELECT id, code, codein, bid, ts FROM quotes ORDER BY id DESC LIMIT 1;`
+	latestQuoteByPairQuery = `SELECT id, code, codein, bid, ts FROM quotes WHERE code = ? AND codein = ? ORDER BY id DESC LIMIT 1;`
+	listQuotesQuery        = `SELECT id, code, codein, bid, ts FROM quotes WHERE ts >= ? AND ts <= ? AND id > ? AND (? = '' OR code = ?) AND (? = '' OR codein = ?) ORDER BY id ASC LIMIT ?;`
+)
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func newSQLiteStore(dsn string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir banco sqlite: %w", err)
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	if _, err := db.Exec(createTableQuery); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("erro ao criar tabela: %w", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) Insert(ctx context.Context, q Quote) error {
+	_, err := s.db.ExecContext(ctx, insertQuoteQuery, q.Code, q.Codein, q.Bid, q.Ts)
+	return err
+}
+
+func (s *sqliteStore) Latest(ctx context.Context, code, codein string) (Quote, error) {
+	var row *sql.Row
+	if code == "" && codein == "" {
+		row = s.db.QueryRowContext(ctx, latestQuoteQuery)
+	} else {
+		row = s.db.QueryRowContext(ctx, latestQuoteByPairQuery, code, codein)
+	}
+
+	var q Quote
+	if err := row.Scan(&q.ID, &q.Code, &q.Codein, &q.Bid, &q.Ts); err != nil {
+		if err == sql.ErrNoRows {
+			return Quote{}, errNoQuotes
+		}
+		return Quote{}, err
+	}
+	return q, nil
+}
+
+func (s *sqliteStore) List(ctx context.Context, params ListParams) ([]Quote, error) {
+	rows, err := s.db.QueryContext(ctx, listQuotesQuery,
+		params.Since, params.Until, params.AfterID,
+		params.Code, params.Code, params.Codein, params.Codein,
+		params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []Quote
+	for rows.Next() {
+		var q Quote
+		if err := rows.Scan(&q.ID, &q.Code, &q.Codein, &q.Bid, &q.Ts); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// --- json ---
+
+// jsonStore persiste cada cotação como uma linha JSON (JSON Lines) em
+// arquivo local, útil para rodar o servidor sem CGO/sqlite ou em testes.
+type jsonStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newJSONStore(path string) (*jsonStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo json %s: %w", path, err)
+	}
+	f.Close()
+	return &jsonStore{path: path}, nil
+}
+
+func (s *jsonStore) Insert(ctx context.Context, q Quote) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir arquivo json para escrita: %w", err)
+	}
+	defer f.Close()
+
+	q.ID = int64(time.Now().UnixNano())
+	enc := json.NewEncoder(f)
+	if err := enc.Encode(q); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+func (s *jsonStore) readAll() ([]Quote, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir arquivo json para leitura: %w", err)
+	}
+	defer f.Close()
+
+	var quotes []Quote
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var q Quote
+		if err := json.Unmarshal(scanner.Bytes(), &q); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar linha do arquivo json: %w", err)
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, scanner.Err()
+}
+
+func (s *jsonStore) Latest(ctx context.Context, code, codein string) (Quote, error) {
+	if err := ctx.Err(); err != nil {
+		return Quote{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, err := s.readAll()
+	if err != nil {
+		return Quote{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Quote{}, err
+	}
+
+	var latest Quote
+	var found bool
+	for _, q := range quotes {
+		if code != "" && q.Code != code {
+			continue
+		}
+		if codein != "" && q.Codein != codein {
+			continue
+		}
+		if !found || q.ID > latest.ID {
+			latest = q
+			found = true
+		}
+	}
+	if !found {
+		return Quote{}, errNoQuotes
+	}
+	return latest, nil
+}
+
+func (s *jsonStore) List(ctx context.Context, params ListParams) ([]Quote, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotes, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(quotes, func(i, j int) bool { return quotes[i].ID < quotes[j].ID })
+
+	var filtered []Quote
+	for _, q := range quotes {
+		if q.ID <= params.AfterID {
+			continue
+		}
+		if q.Ts.Before(params.Since) || q.Ts.After(params.Until) {
+			continue
+		}
+		if params.Code != "" && q.Code != params.Code {
+			continue
+		}
+		if params.Codein != "" && q.Codein != params.Codein {
+			continue
+		}
+		filtered = append(filtered, q)
+		if params.Limit > 0 && len(filtered) >= params.Limit {
+			break
+		}
+	}
+	return filtered, nil
+}
+
+func (s *jsonStore) Close() error {
+	return nil
+}
+
+// --- postgres ---
+
+const (
+	createTablePostgresQuery = `
+CREATE TABLE IF NOT EXISTS quotes (
+	id SERIAL PRIMARY KEY,
+	code TEXT NOT NULL,
+	codein TEXT NOT NULL,
+	bid TEXT NOT NULL,
+	ts TIMESTAMPTZ NOT NULL
+);`
+	insertQuotePostgresQuery       = `INSERT INTO quotes(code, codein, bid, ts) VALUES($1, $2, $3, $4);`
+	latestQuotePostgresQuery       = `SELECT id, code, codein, bid, ts FROM quotes ORDER BY id DESC LIMIT 1;`
+	latestQuoteByPairPostgresQuery = `SELECT id, code, codein, bid, ts FROM quotes WHERE code = $1 AND codein = $2 ORDER BY id DESC LIMIT 1;`
+	listQuotesPostgresQuery        = `SELECT id, code, codein, bid, ts FROM quotes WHERE ts >= $1 AND ts <= $2 AND id > $3 AND ($4 = '' OR code = $4) AND ($5 = '' OR codein = $5) ORDER BY id ASC LIMIT $6;`
+)
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+func newPostgresStore(databaseURL string) (*postgresStore, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir conexão postgres: %w", err)
+	}
+
+	if _, err := db.Exec(createTablePostgresQuery); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("erro ao criar tabela no postgres: %w", err)
+	}
+
+	return &postgresStore{db: db}, nil
+}
+
+func (s *postgresStore) Insert(ctx context.Context, q Quote) error {
+	_, err := s.db.ExecContext(ctx, insertQuotePostgresQuery, q.Code, q.Codein, q.Bid, q.Ts)
+	return err
+}
+
+func (s *postgresStore) Latest(ctx context.Context, code, codein string) (Quote, error) {
+	var row *sql.Row
+	if code == "" && codein == "" {
+		row = s.db.QueryRowContext(ctx, latestQuotePostgresQuery)
+	} else {
+		row = s.db.QueryRowContext(ctx, latestQuoteByPairPostgresQuery, code, codein)
+	}
+
+	var q Quote
+	if err := row.Scan(&q.ID, &q.Code, &q.Codein, &q.Bid, &q.Ts); err != nil {
+		if err == sql.ErrNoRows {
+			return Quote{}, errNoQuotes
+		}
+		return Quote{}, err
+	}
+	return q, nil
+}
+
+func (s *postgresStore) List(ctx context.Context, params ListParams) ([]Quote, error) {
+	rows, err := s.db.QueryContext(ctx, listQuotesPostgresQuery,
+		params.Since, params.Until, params.AfterID,
+		params.Code, params.Codein,
+		params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []Quote
+	for rows.Next() {
+		var q Quote
+		if err := rows.Scan(&q.ID, &q.Code, &q.Codein, &q.Bid, &q.Ts); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, q)
+	}
+	return quotes, rows.Err()
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestStores devolve um sqliteStore e um jsonStore recém-criados em
+// diretórios temporários, para exercitar ambas as implementações com o
+// mesmo conjunto de casos.
+func newTestStores(t *testing.T) map[string]QuoteStore {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	sqliteStore, err := newSQLiteStore("file:" + filepath.Join(dir, "quotes.db"))
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+
+	jsonStore, err := newJSONStore(filepath.Join(dir, "quotes.jsonl"))
+	if err != nil {
+		t.Fatalf("newJSONStore: %v", err)
+	}
+	t.Cleanup(func() { jsonStore.Close() })
+
+	return map[string]QuoteStore{
+		"sqlite": sqliteStore,
+		"json":   jsonStore,
+	}
+}
+
+func TestQuoteStore_LatestWithoutPairReturnsMostRecentAcrossAllPairs(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Now().UTC().Truncate(time.Second)
+
+			if err := store.Insert(ctx, Quote{Code: "USD", Codein: "BRL", Bid: "5.00", Ts: base}); err != nil {
+				t.Fatalf("Insert USD-BRL: %v", err)
+			}
+			if err := store.Insert(ctx, Quote{Code: "EUR", Codein: "BRL", Bid: "6.00", Ts: base.Add(time.Second)}); err != nil {
+				t.Fatalf("Insert EUR-BRL: %v", err)
+			}
+
+			got, err := store.Latest(ctx, "", "")
+			if err != nil {
+				t.Fatalf("Latest(\"\", \"\"): %v", err)
+			}
+			if got.Code != "EUR" || got.Codein != "BRL" {
+				t.Errorf("Latest sem filtro = %s-%s, want EUR-BRL (o registro mais recente)", got.Code, got.Codein)
+			}
+		})
+	}
+}
+
+func TestQuoteStore_LatestWithPairFiltersToThatPair(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Now().UTC().Truncate(time.Second)
+
+			if err := store.Insert(ctx, Quote{Code: "USD", Codein: "BRL", Bid: "5.00", Ts: base}); err != nil {
+				t.Fatalf("Insert USD-BRL: %v", err)
+			}
+			if err := store.Insert(ctx, Quote{Code: "EUR", Codein: "BRL", Bid: "6.00", Ts: base.Add(time.Second)}); err != nil {
+				t.Fatalf("Insert EUR-BRL: %v", err)
+			}
+
+			got, err := store.Latest(ctx, "USD", "BRL")
+			if err != nil {
+				t.Fatalf("Latest(\"USD\", \"BRL\"): %v", err)
+			}
+			if got.Code != "USD" || got.Bid != "5.00" {
+				t.Errorf("Latest(USD, BRL) = %s-%s bid=%s, want USD-BRL bid=5.00", got.Code, got.Codein, got.Bid)
+			}
+		})
+	}
+}
+
+func TestQuoteStore_LatestWithNoRowsReturnsErrNoQuotes(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			_, err := store.Latest(context.Background(), "", "")
+			if !errors.Is(err, errNoQuotes) {
+				t.Errorf("Latest em store vazio: err = %v, want errNoQuotes", err)
+			}
+		})
+	}
+}
+
+func TestQuoteStore_ListFiltersByPairAndPaginatesByAfterID(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			base := time.Now().UTC().Truncate(time.Second)
+
+			for i := 0; i < 3; i++ {
+				if err := store.Insert(ctx, Quote{Code: "USD", Codein: "BRL", Bid: "5.00", Ts: base.Add(time.Duration(i) * time.Second)}); err != nil {
+					t.Fatalf("Insert USD-BRL #%d: %v", i, err)
+				}
+			}
+			if err := store.Insert(ctx, Quote{Code: "EUR", Codein: "BRL", Bid: "6.00", Ts: base.Add(4 * time.Second)}); err != nil {
+				t.Fatalf("Insert EUR-BRL: %v", err)
+			}
+
+			all, err := store.List(ctx, ListParams{Since: base.Add(-time.Hour), Until: base.Add(time.Hour), Limit: 100})
+			if err != nil {
+				t.Fatalf("List sem filtro: %v", err)
+			}
+			if len(all) != 4 {
+				t.Fatalf("List sem filtro = %d registros, want 4", len(all))
+			}
+
+			usdOnly, err := store.List(ctx, ListParams{Since: base.Add(-time.Hour), Until: base.Add(time.Hour), Limit: 100, Code: "USD", Codein: "BRL"})
+			if err != nil {
+				t.Fatalf("List(pair=USD-BRL): %v", err)
+			}
+			if len(usdOnly) != 3 {
+				t.Fatalf("List(pair=USD-BRL) = %d registros, want 3", len(usdOnly))
+			}
+			for _, q := range usdOnly {
+				if q.Code != "USD" || q.Codein != "BRL" {
+					t.Errorf("List(pair=USD-BRL) devolveu %s-%s", q.Code, q.Codein)
+				}
+			}
+
+			afterFirst, err := store.List(ctx, ListParams{Since: base.Add(-time.Hour), Until: base.Add(time.Hour), Limit: 100, Code: "USD", Codein: "BRL", AfterID: usdOnly[0].ID})
+			if err != nil {
+				t.Fatalf("List(afterID): %v", err)
+			}
+			if len(afterFirst) != 2 {
+				t.Fatalf("List(afterID=%d, pair=USD-BRL) = %d registros, want 2", usdOnly[0].ID, len(afterFirst))
+			}
+		})
+	}
+}
+
+func TestNewQuoteStore_SelectsBackendBySchemeAndRejectsUnknown(t *testing.T) {
+	dir := t.TempDir()
+
+	sqliteBackend, err := newQuoteStore("sqlite://" + filepath.Join(dir, "a.db"))
+	if err != nil {
+		t.Fatalf("newQuoteStore(sqlite://): %v", err)
+	}
+	defer sqliteBackend.Close()
+	if _, ok := sqliteBackend.(*sqliteStore); !ok {
+		t.Errorf("newQuoteStore(sqlite://) = %T, want *sqliteStore", sqliteBackend)
+	}
+
+	jsonPath := filepath.Join(dir, "b.jsonl")
+	jsonBackend, err := newQuoteStore("json://" + jsonPath)
+	if err != nil {
+		t.Fatalf("newQuoteStore(json://): %v", err)
+	}
+	defer jsonBackend.Close()
+	if _, ok := jsonBackend.(*jsonStore); !ok {
+		t.Errorf("newQuoteStore(json://) = %T, want *jsonStore", jsonBackend)
+	}
+	if _, err := os.Stat(jsonPath); err != nil {
+		t.Errorf("newQuoteStore(json://) não criou o arquivo: %v", err)
+	}
+
+	if _, err := newQuoteStore("mongo://localhost"); err == nil {
+		t.Error("newQuoteStore com esquema desconhecido deveria falhar")
+	}
+}